@@ -0,0 +1,131 @@
+package captcha
+
+import (
+	"io"
+)
+
+const (
+	sampleRate = 8000 // samples per second
+
+	// Silence and noise segments are randomized to defeat naive
+	// segmentation; these bound how long they may be, in samples.
+	minSilence = sampleRate / 10
+	maxSilence = sampleRate / 2
+	noiseLevel = 2560 // amplitude of the interleaved white noise, out of 32767
+
+	preambleMin = sampleRate / 2
+	preambleMax = sampleRate
+)
+
+// AudioCaptcha is an audio rendition of a captcha, analogous to
+// CaptchaImage but producing a PCM sample stream instead of a bitmap.
+//
+// See the BUG note on clipsEN/clipsZH in audio_data.go: the per-digit
+// clips are synthesized tones, not recorded speech, so each digit is
+// heard as a distinct beep rather than as a spoken digit.
+type AudioCaptcha struct {
+	lang    string
+	samples []int16
+}
+
+// NewAudio returns a new audio captcha rendering the given sequence of
+// numbers (each in range 0-9) as one clip per digit, in the given
+// language. If lang has no dedicated clip set, English clips are used.
+// See AudioCaptcha's doc comment: the clips are tones, not spoken
+// digits.
+func NewAudio(numbers []byte, lang string) *AudioCaptcha {
+	a := new(AudioCaptcha)
+	a.lang = lang
+	clips := clipsForLang(lang)
+
+	a.appendPreamble()
+	for i, n := range numbers {
+		if i > 0 {
+			a.appendSilence()
+		}
+		a.appendClip(clips[n])
+	}
+	a.appendPreamble()
+	return a
+}
+
+func (a *AudioCaptcha) appendPreamble() {
+	a.appendNoise(rnd(preambleMin, preambleMax))
+}
+
+func (a *AudioCaptcha) appendSilence() {
+	n := rnd(minSilence, maxSilence)
+	for i := 0; i < n; i++ {
+		a.samples = append(a.samples, 0)
+	}
+}
+
+func (a *AudioCaptcha) appendNoise(n int) {
+	for i := 0; i < n; i++ {
+		a.samples = append(a.samples, int16(rnd(-noiseLevel, noiseLevel)))
+	}
+}
+
+func (a *AudioCaptcha) appendClip(clip []byte) {
+	// Clips are stored as little-endian 16-bit PCM, one sample per two
+	// bytes, matching the convention of the charset glyph tables in
+	// charset_data.go: a flat []byte that gets reinterpreted by the
+	// renderer.
+	for i := 0; i+1 < len(clip); i += 2 {
+		sample := int16(clip[i]) | int16(clip[i+1])<<8
+		// Mix in a trace of low-amplitude noise under the spoken digit
+		// as well, so the loud/silent envelope alone doesn't betray
+		// where digits start and stop.
+		sample += int16(rnd(-noiseLevel/8, noiseLevel/8))
+		a.samples = append(a.samples, sample)
+	}
+}
+
+// WAVEncode writes the audio captcha in WAV (RIFF/PCM) format into the
+// given writer.
+func (a *AudioCaptcha) WAVEncode(w io.Writer) error {
+	dataSize := len(a.samples) * 2
+	buf := make([]byte, 0, 44+dataSize)
+
+	buf = append(buf, "RIFF"...)
+	buf = appendUint32(buf, uint32(36+dataSize))
+	buf = append(buf, "WAVE"...)
+
+	buf = append(buf, "fmt "...)
+	buf = appendUint32(buf, 16) // PCM header size
+	buf = appendUint16(buf, 1)  // audio format: PCM
+	buf = appendUint16(buf, 1)  // channels: mono
+	buf = appendUint32(buf, sampleRate)
+	buf = appendUint32(buf, sampleRate*2) // byte rate
+	buf = appendUint16(buf, 2)            // block align
+	buf = appendUint16(buf, 16)           // bits per sample
+
+	buf = append(buf, "data"...)
+	buf = appendUint32(buf, uint32(dataSize))
+	for _, s := range a.samples {
+		buf = appendUint16(buf, uint16(s))
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	return append(buf, byte(v), byte(v>>8))
+}
+
+// clipsForLang returns the per-digit clip table for the given language,
+// falling back to English when the language is not recognized.
+func clipsForLang(lang string) [10][]byte {
+	switch lang {
+	case "zh":
+		return clipsZH
+	case "en":
+		return clipsEN
+	}
+	return clipsEN
+}
@@ -0,0 +1,53 @@
+package captcha
+
+import "math"
+
+// BUG(maintainers): clipsEN and clipsZH are synthesized tones, not
+// recorded speech: a listener hears a distinct beep per digit, not a
+// spoken digit.
+
+// clipsEN and clipsZH hold the per-digit PCM clip tables used by
+// NewAudio, analogous to the charset glyph tables used for images: one flat
+// []byte entry per glyph (here, per spoken digit), indexed 0-9.
+//
+// Each entry is little-endian 16-bit mono PCM at sampleRate. These are
+// NOT recorded speech - see the package-level BUG note above - but
+// synthesized tones, one fixed pitch per digit, generated at init time
+// so the package ships no binary assets. Swapping in real recordings
+// only requires replacing the contents of these two arrays with actual
+// spoken-digit PCM data.
+var (
+	clipsEN [10][]byte
+	clipsZH [10][]byte
+)
+
+// digitToneHz gives each digit a distinct fundamental frequency so that
+// the placeholder clips are at least distinguishable from one another.
+var digitToneHz = [10]float64{
+	220, 247, 262, 294, 330, 349, 392, 440, 494, 523,
+}
+
+func init() {
+	for n := 0; n < 10; n++ {
+		clipsEN[n] = makeToneClip(digitToneHz[n], 0)
+		clipsZH[n] = makeToneClip(digitToneHz[n], 40) // detuned, stands in for a second voice
+	}
+}
+
+// makeToneClip synthesizes a short decaying tone at the given frequency
+// (offset by detuneHz) to stand in for a recorded spoken digit.
+func makeToneClip(freqHz, detuneHz float64) []byte {
+	const duration = 0.3 // seconds
+	n := int(duration * sampleRate)
+	freq := freqHz + detuneHz
+	clip := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		t := float64(i) / sampleRate
+		decay := 1 - t/duration
+		v := math.Sin(2*math.Pi*freq*t) * decay
+		s := int16(v * 16000)
+		clip[i*2] = byte(s)
+		clip[i*2+1] = byte(s >> 8)
+	}
+	return clip
+}
@@ -0,0 +1,100 @@
+package captcha
+
+// blackChar marks a set dot in a glyph's dot-matrix representation;
+// any other value is treated as background.
+const blackChar = 1
+
+// Charset supplies the drawable glyphs NewImage renders into dots. A
+// glyph is a flat []byte of Width()*Height() entries, row-major, with
+// blackChar marking a dot that should be drawn.
+type Charset interface {
+	// Glyph returns the dot-matrix bitmap for r, or nil if the charset
+	// has no glyph for r.
+	Glyph(r rune) []byte
+
+	// Runes returns the set of runes NewRandomImage may pick from when
+	// generating a random captcha in this charset.
+	Runes() []rune
+
+	Width() int
+	Height() int
+}
+
+// CharsetID selects one of the built-in charsets for NewRandomImage.
+type CharsetID int
+
+const (
+	// CharsetDigits renders the digits 0-9, the original captcha
+	// behavior.
+	CharsetDigits CharsetID = iota
+
+	// CharsetUpper renders uppercase Latin letters A-Z.
+	CharsetUpper
+
+	// CharsetAlnum renders a mix of digits and uppercase Latin letters.
+	CharsetAlnum
+
+	// CharsetChinese renders a small set of common Chinese characters.
+	// Its glyphs are hand-drawn 7x7 dot patterns, not rasterized from a
+	// font (see the BUG note on chineseRows in charset_data.go).
+	CharsetChinese
+)
+
+func charsetByID(id CharsetID) Charset {
+	switch id {
+	case CharsetUpper:
+		return upperCharset
+	case CharsetAlnum:
+		return alnumCharset
+	case CharsetChinese:
+		return chineseCharset
+	}
+	return digitsCharset
+}
+
+// randomChars picks n random runes from cs.
+func randomChars(cs Charset, n int) []rune {
+	runes := cs.Runes()
+	out := make([]rune, n)
+	for i := range out {
+		out[i] = runes[rnd(0, len(runes)-1)]
+	}
+	return out
+}
+
+// randomDigits returns n random digits in range 0-9, the payload format
+// New, Verify and the default Server rendering operate on.
+func randomDigits(n int) []byte {
+	chars := randomChars(digitsCharset, n)
+	digits := make([]byte, n)
+	for i, r := range chars {
+		digits[i] = byte(r - '0')
+	}
+	return digits
+}
+
+// multiCharset composes several charsets of identical dimensions into
+// one, used by CharsetAlnum to combine digits and letters.
+type multiCharset struct {
+	sets []Charset
+}
+
+func (m *multiCharset) Glyph(r rune) []byte {
+	for _, s := range m.sets {
+		if g := s.Glyph(r); g != nil {
+			return g
+		}
+	}
+	return nil
+}
+
+func (m *multiCharset) Runes() []rune {
+	var out []rune
+	for _, s := range m.sets {
+		out = append(out, s.Runes()...)
+	}
+	return out
+}
+
+func (m *multiCharset) Width() int  { return m.sets[0].Width() }
+func (m *multiCharset) Height() int { return m.sets[0].Height() }
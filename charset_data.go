@@ -0,0 +1,130 @@
+package captcha
+
+// bitmapCharset is a Charset backed by a fixed-size dot-matrix bitmap
+// per rune, the same representation the original digit-only font table
+// used.
+type bitmapCharset struct {
+	width, height int
+	runes         []rune
+	glyphs        map[rune][]byte
+}
+
+func newBitmapCharset(width, height int, order string, rows map[rune][]string) *bitmapCharset {
+	cs := &bitmapCharset{
+		width:  width,
+		height: height,
+		glyphs: make(map[rune][]byte, len(rows)),
+	}
+	for _, r := range order {
+		lines, ok := rows[r]
+		if !ok {
+			continue
+		}
+		glyph := make([]byte, width*height)
+		for y := 0; y < height && y < len(lines); y++ {
+			line := lines[y]
+			for x := 0; x < width && x < len(line); x++ {
+				if line[x] != '0' {
+					glyph[y*width+x] = blackChar
+				}
+			}
+		}
+		cs.glyphs[r] = glyph
+		cs.runes = append(cs.runes, r)
+	}
+	return cs
+}
+
+func (b *bitmapCharset) Glyph(r rune) []byte { return b.glyphs[r] }
+func (b *bitmapCharset) Runes() []rune       { return b.runes }
+func (b *bitmapCharset) Width() int          { return b.width }
+func (b *bitmapCharset) Height() int         { return b.height }
+
+// digitsCharset, upperCharset and chineseCharset are the built-in
+// charsets selectable via CharsetID. alnumCharset composes the first
+// two.
+var (
+	digitsCharset   *bitmapCharset
+	upperCharset    *bitmapCharset
+	alnumCharset    *multiCharset
+	chineseCharset  *bitmapCharset
+	operatorCharset *bitmapCharset
+	mathCharset     *multiCharset
+)
+
+func init() {
+	digitsCharset = newBitmapCharset(5, 7, "0123456789", digitRows)
+	upperCharset = newBitmapCharset(5, 7, "ABCDEFGHIJKLMNOPQRSTUVWXYZ", upperRows)
+	alnumCharset = &multiCharset{sets: []Charset{digitsCharset, upperCharset}}
+	chineseCharset = newBitmapCharset(7, 7, "一二三十人大", chineseRows)
+	operatorCharset = newBitmapCharset(5, 7, "+-×=", operatorRows)
+	mathCharset = &multiCharset{sets: []Charset{digitsCharset, operatorCharset}}
+}
+
+// operatorRows extends the digit font with the symbols NewMathImage
+// needs to render an arithmetic expression.
+var operatorRows = map[rune][]string{
+	'+': {"00000", "00100", "00100", "11111", "00100", "00100", "00000"},
+	'-': {"00000", "00000", "00000", "11111", "00000", "00000", "00000"},
+	'×': {"00000", "10001", "01010", "00100", "01010", "10001", "00000"},
+	'=': {"00000", "00000", "11111", "00000", "11111", "00000", "00000"},
+}
+
+var digitRows = map[rune][]string{
+	'0': {"01110", "10001", "10011", "10101", "11001", "10001", "01110"},
+	'1': {"00100", "01100", "00100", "00100", "00100", "00100", "01110"},
+	'2': {"01110", "10001", "00001", "00010", "00100", "01000", "11111"},
+	'3': {"11111", "00010", "00100", "00010", "00001", "10001", "01110"},
+	'4': {"00010", "00110", "01010", "10010", "11111", "00010", "00010"},
+	'5': {"11111", "10000", "11110", "00001", "00001", "10001", "01110"},
+	'6': {"00110", "01000", "10000", "11110", "10001", "10001", "01110"},
+	'7': {"11111", "00001", "00010", "00100", "01000", "01000", "01000"},
+	'8': {"01110", "10001", "10001", "01110", "10001", "10001", "01110"},
+	'9': {"01110", "10001", "10001", "01111", "00001", "00010", "01100"},
+}
+
+var upperRows = map[rune][]string{
+	'A': {"01110", "10001", "10001", "11111", "10001", "10001", "10001"},
+	'B': {"11110", "10001", "10001", "11110", "10001", "10001", "11110"},
+	'C': {"01111", "10000", "10000", "10000", "10000", "10000", "01111"},
+	'D': {"11110", "10001", "10001", "10001", "10001", "10001", "11110"},
+	'E': {"11111", "10000", "10000", "11110", "10000", "10000", "11111"},
+	'F': {"11111", "10000", "10000", "11110", "10000", "10000", "10000"},
+	'G': {"01111", "10000", "10000", "10111", "10001", "10001", "01111"},
+	'H': {"10001", "10001", "10001", "11111", "10001", "10001", "10001"},
+	'I': {"01110", "00100", "00100", "00100", "00100", "00100", "01110"},
+	'J': {"00001", "00001", "00001", "00001", "10001", "10001", "01110"},
+	'K': {"10001", "10010", "10100", "11000", "10100", "10010", "10001"},
+	'L': {"10000", "10000", "10000", "10000", "10000", "10000", "11111"},
+	'M': {"10001", "11011", "10101", "10101", "10001", "10001", "10001"},
+	'N': {"10001", "11001", "10101", "10101", "10011", "10001", "10001"},
+	'O': {"01110", "10001", "10001", "10001", "10001", "10001", "01110"},
+	'P': {"11110", "10001", "10001", "11110", "10000", "10000", "10000"},
+	'Q': {"01110", "10001", "10001", "10001", "10101", "10010", "01101"},
+	'R': {"11110", "10001", "10001", "11110", "10100", "10010", "10001"},
+	'S': {"01111", "10000", "10000", "01110", "00001", "00001", "11110"},
+	'T': {"11111", "00100", "00100", "00100", "00100", "00100", "00100"},
+	'U': {"10001", "10001", "10001", "10001", "10001", "10001", "01110"},
+	'V': {"10001", "10001", "10001", "10001", "10001", "01010", "00100"},
+	'W': {"10001", "10001", "10001", "10101", "10101", "11011", "10001"},
+	'X': {"10001", "10001", "01010", "00100", "01010", "10001", "10001"},
+	'Y': {"10001", "10001", "01010", "00100", "00100", "00100", "00100"},
+	'Z': {"11111", "00001", "00010", "00100", "01000", "10000", "11111"},
+}
+
+// BUG(maintainers): chineseRows is hand-drawn, not rasterized from a
+// font: CharsetChinese covers only these six characters, each drawn as
+// a coarse 7x7 dot pattern.
+
+// chineseRows holds a hand-drawn 7x7 dot pattern for each of the six
+// characters CharsetChinese renders (see the BUG note above). A table
+// of rows rasterized from a font at the same width and height can
+// replace it without other changes.
+var chineseRows = map[rune][]string{
+	'一': {"0000000", "0000000", "0000000", "1111111", "0000000", "0000000", "0000000"},
+	'二': {"0000000", "0000000", "1111111", "0000000", "0000000", "1111111", "0000000"},
+	'三': {"0000000", "1111111", "0000000", "1111111", "0000000", "1111111", "0000000"},
+	'十': {"0001000", "0001000", "0001000", "1111111", "0001000", "0001000", "0001000"},
+	'人': {"0001000", "0001000", "0010100", "0010100", "0100010", "0100010", "1000001"},
+	'大': {"0001000", "0001000", "0001000", "1111111", "0010100", "0100010", "1000001"},
+}
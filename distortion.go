@@ -0,0 +1,183 @@
+package captcha
+
+import (
+	"image/color"
+	"math"
+)
+
+// Distortion transforms a rendered captcha image in place, after its
+// characters have been drawn. NewImageWithOptions applies a pipeline of
+// Distortions in order, letting operators raise anti-OCR strength
+// without forking the package.
+type Distortion interface {
+	Apply(img *CaptchaImage)
+}
+
+// Options configures NewImageWithOptions.
+type Options struct {
+	// Background is the pipeline applied, in order, to fill in the
+	// image before any characters are drawn onto it. A nil slice falls
+	// back to DefaultBackground.
+	Background []Distortion
+
+	// Distortions is the pipeline applied, in order, to the rendered
+	// image. A nil slice falls back to DefaultDistortions.
+	Distortions []Distortion
+}
+
+// DefaultBackground returns the pipeline newCharsetImage has always
+// used: 10 random circles of random brightness.
+func DefaultBackground() []Distortion {
+	return []Distortion{CircleBackground{N: 10}}
+}
+
+// DefaultDistortions returns the pipeline NewImage has always used: a
+// single strike-through line across the digits.
+func DefaultDistortions() []Distortion {
+	return []Distortion{StrikeThroughLine{}}
+}
+
+// CircleBackground fills the image with N circles of random position,
+// radius (up to the image's dot size) and brightness, the original
+// (and still default) background fill.
+type CircleBackground struct {
+	N int
+}
+
+func (b CircleBackground) Apply(img *CaptchaImage) {
+	img.fillWithCircles(b.N, img.dotSize)
+}
+
+// StrikeThroughLine draws a wandering strike-through line across the
+// image, the original (and still default) distortion.
+type StrikeThroughLine struct{}
+
+func (StrikeThroughLine) Apply(img *CaptchaImage) {
+	img.strikeThrough()
+}
+
+// SineWarpHorizontal shifts each output row y horizontally, sampling
+// the source pixel at x' = x + A*sin(2*pi*y/lambda + phi), with A,
+// lambda and phi randomized per application.
+type SineWarpHorizontal struct{}
+
+func (SineWarpHorizontal) Apply(img *CaptchaImage) {
+	b := img.Bounds()
+	w, h := b.Max.X, b.Max.Y
+	amp := float64(img.rnd(img.dotSize, img.dotSize*2))
+	lambda := float64(img.rnd(max(h/2, 1), max(h, 2)))
+	phi := img.rng.Float64() * 2 * math.Pi
+
+	src := snapshot(img)
+	for y := 0; y < h; y++ {
+		offset := int(amp * math.Sin(2*math.Pi*float64(y)/lambda+phi))
+		for x := 0; x < w; x++ {
+			img.Set(x, y, src.at(clamp(x+offset, w), y))
+		}
+	}
+}
+
+// SineWarpVertical is SineWarpHorizontal transposed: it shifts each
+// output column x vertically instead of each row horizontally.
+type SineWarpVertical struct{}
+
+func (SineWarpVertical) Apply(img *CaptchaImage) {
+	b := img.Bounds()
+	w, h := b.Max.X, b.Max.Y
+	amp := float64(img.rnd(img.dotSize, img.dotSize*2))
+	lambda := float64(img.rnd(max(w/2, 1), max(w, 2)))
+	phi := img.rng.Float64() * 2 * math.Pi
+
+	src := snapshot(img)
+	for x := 0; x < w; x++ {
+		offset := int(amp * math.Sin(2*math.Pi*float64(x)/lambda+phi))
+		for y := 0; y < h; y++ {
+			img.Set(x, y, src.at(x, clamp(y+offset, h)))
+		}
+	}
+}
+
+// BezierInterference draws 2-4 quadratic Bezier curves across the image
+// as interference, stamping a filled circle of randomized radius at
+// each of ~200 steps along each curve.
+type BezierInterference struct{}
+
+func (BezierInterference) Apply(img *CaptchaImage) {
+	b := img.Bounds()
+	w, h := b.Max.X, b.Max.Y
+	for i := img.rnd(2, 4); i > 0; i-- {
+		p0x, p0y := img.rnd(0, w-1), img.rnd(0, h-1)
+		p1x, p1y := img.rnd(0, w-1), img.rnd(0, h-1)
+		p2x, p2y := img.rnd(0, w-1), img.rnd(0, h-1)
+		const steps = 200
+		for s := 0; s <= steps; s++ {
+			t := float64(s) / steps
+			mt := 1 - t
+			x := mt*mt*float64(p0x) + 2*mt*t*float64(p1x) + t*t*float64(p2x)
+			y := mt*mt*float64(p0y) + 2*mt*t*float64(p1y) + t*t*float64(p2y)
+			r := img.rnd(1, max(img.dotSize/2, 1))
+			img.drawCircle(img.primaryColor, int(x), int(y), r)
+		}
+	}
+}
+
+// SaltPepperNoise flips a random fraction of pixels to black or white.
+type SaltPepperNoise struct {
+	// Density is the fraction of pixels flipped, in [0, 1].
+	Density float64
+}
+
+func (d SaltPepperNoise) Apply(img *CaptchaImage) {
+	b := img.Bounds()
+	w, h := b.Max.X, b.Max.Y
+	n := int(float64(w*h) * d.Density)
+	for i := 0; i < n; i++ {
+		x, y := img.rnd(0, w-1), img.rnd(0, h-1)
+		if img.rng.Intn(2) == 0 {
+			img.Set(x, y, color.NRGBA{A: 0xFF})
+		} else {
+			img.Set(x, y, color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF})
+		}
+	}
+}
+
+// imageSnapshot is a plain copy of an image's pixels, used by the warp
+// distortions so they sample the pre-warp image while writing the
+// post-warp one.
+type imageSnapshot struct {
+	w, h   int
+	pixels []color.Color
+}
+
+func snapshot(img *CaptchaImage) *imageSnapshot {
+	b := img.Bounds()
+	s := &imageSnapshot{w: b.Max.X, h: b.Max.Y}
+	s.pixels = make([]color.Color, s.w*s.h)
+	for y := 0; y < s.h; y++ {
+		for x := 0; x < s.w; x++ {
+			s.pixels[y*s.w+x] = img.At(x, y)
+		}
+	}
+	return s
+}
+
+func (s *imageSnapshot) at(x, y int) color.Color {
+	return s.pixels[y*s.w+x]
+}
+
+func clamp(v, max int) int {
+	if v < 0 {
+		return 0
+	}
+	if v >= max {
+		return max - 1
+	}
+	return v
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,15 @@
+// Package captcha generates and verifies numeric, alphanumeric, Chinese
+// and arithmetic captchas, rendered as dot-matrix images or audio,
+// checked against a pluggable Store.
+//
+// # Known limitations
+//
+// AudioCaptcha (see the BUG note on clipsEN/clipsZH in audio_data.go)
+// renders each digit as a synthesized tone, one pitch per digit, not as
+// recorded speech: a listener hears a distinct beep per digit, not a
+// spoken digit.
+//
+// CharsetChinese (see the BUG note on chineseRows in charset_data.go)
+// draws its six characters from hand-drawn 7x7 dot patterns; no glyphs
+// are rasterized from a font, and the package imports no font library.
+package captcha
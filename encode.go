@@ -0,0 +1,56 @@
+package captcha
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"io"
+)
+
+// JPEGEncode writes the captcha image in JPEG format, at the given
+// quality (1-100, as accepted by image/jpeg), into the given writer.
+func (img *CaptchaImage) JPEGEncode(w io.Writer, quality int) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
+
+// gifFrames is how many frames GIFEncode animates the strike-through
+// line across.
+const gifFrames = 4
+
+// GIFEncode writes the captcha as a lightweight animated GIF: the same
+// image repeated across a few frames, each with one extra, independently
+// drawn strike-through line on top of img's own pixels (including any
+// line its distortion pipeline already drew), so the extra line appears
+// to shift from frame to frame.
+// img itself is left unmodified - each frame draws its line onto a
+// throwaway clone rather than img.
+func (img *CaptchaImage) GIFEncode(w io.Writer) error {
+	anim := gif.GIF{LoopCount: 0}
+	for i := 0; i < gifFrames; i++ {
+		scratch := img.clone()
+		scratch.strikeThrough()
+		frame := image.NewPaletted(img.Bounds(), palette.Plan9)
+		for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+			for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+				frame.Set(x, y, scratch.At(x, y))
+			}
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 15) // 150ms
+	}
+	return gif.EncodeAll(w, &anim)
+}
+
+// Base64DataURL returns the captcha image PNG-encoded as a
+// "data:image/png;base64,..." URL, suitable for embedding directly in
+// an <img> tag without a second HTTP round-trip.
+func (img *CaptchaImage) Base64DataURL() (string, error) {
+	var buf bytes.Buffer
+	if err := img.PNGEncode(&buf); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
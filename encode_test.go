@@ -0,0 +1,29 @@
+package captcha
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGIFEncodeDoesNotMutateImage(t *testing.T) {
+	img := NewImage([]byte{1, 2, 3, 4, 5, 6}, StdWidth, StdHeight)
+
+	var before bytes.Buffer
+	if err := img.PNGEncode(&before); err != nil {
+		t.Fatalf("PNGEncode before GIFEncode: %v", err)
+	}
+
+	var gifOut bytes.Buffer
+	if err := img.GIFEncode(&gifOut); err != nil {
+		t.Fatalf("GIFEncode: %v", err)
+	}
+
+	var after bytes.Buffer
+	if err := img.PNGEncode(&after); err != nil {
+		t.Fatalf("PNGEncode after GIFEncode: %v", err)
+	}
+
+	if !bytes.Equal(before.Bytes(), after.Bytes()) {
+		t.Fatalf("GIFEncode mutated the receiver: PNG output differs before vs. after")
+	}
+}
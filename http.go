@@ -0,0 +1,127 @@
+package captcha
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// DefaultLen is the default number of digits in a captcha generated by
+// New when no other length is configured.
+const DefaultLen = 6
+
+// New generates a new captcha, stashes its digit sequence in the current
+// Store, and returns the id used to retrieve its image or audio
+// rendering from a Server, or to Verify an answer against it.
+func New() string {
+	id := newID()
+	globalStore.Set(id, taggedPayload(kindDigits, randomDigits(DefaultLen)))
+	return id
+}
+
+// Verify reports whether answer matches the digits stored under id. A
+// successful verification consumes id so that it cannot be replayed; a
+// failed one counts against id's attempt limit and, once that limit is
+// reached, invalidates id as well. The compare-and-consume happens atomically
+// inside the Store, so two concurrent Verify calls for the same id can
+// never both succeed.
+func Verify(id string, answer []byte) bool {
+	return globalStore.Check(id, func(payload []byte) bool {
+		kind, data, ok := untagPayload(payload)
+		return ok && kind == kindDigits && digitsEqual(data, answer)
+	})
+}
+
+func digitsEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func newID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Server returns an http.Handler that serves captcha images and audio
+// rendered at the given width and height, at paths of the form
+// "/<id>.png" and "/<id>.wav". Requesting "/<id>.png?reload=1" (or
+// ".wav") regenerates the digits stored under id before rendering,
+// letting a front-end offer a "get a new captcha" button without
+// minting a new id.
+func Server(width, height int) http.Handler {
+	return &captchaHandler{width: width, height: height}
+}
+
+type captchaHandler struct {
+	width, height int
+}
+
+func (h *captchaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	var ext string
+	switch {
+	case strings.HasSuffix(path, ".png"):
+		ext = ".png"
+	case strings.HasSuffix(path, ".wav"):
+		ext = ".wav"
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	id := strings.TrimSuffix(path, ext)
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	kind, data, ok := untagPayload(globalStore.Get(id, false))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.FormValue("reload") == "1" {
+		switch kind {
+		case kindDigits:
+			data = randomDigits(DefaultLen)
+		case kindMath:
+			chars, answer := mathExpression()
+			data = encodeMathPayload(chars, answer)
+		}
+		globalStore.Set(id, taggedPayload(kind, data))
+	}
+
+	switch kind {
+	case kindDigits:
+		switch ext {
+		case ".png":
+			w.Header().Set("Content-Type", "image/png")
+			NewImage(data, h.width, h.height).PNGEncode(w)
+		case ".wav":
+			w.Header().Set("Content-Type", "audio/x-wav")
+			NewAudio(data, "en").WAVEncode(w)
+		}
+	case kindMath:
+		chars, _, ok := decodeMathPayload(data)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch ext {
+		case ".png":
+			w.Header().Set("Content-Type", "image/png")
+			newCharsetImage(mathCharset, chars, h.width, h.height, Options{}).PNGEncode(w)
+		case ".wav":
+			http.Error(w, "audio rendering is not available for math captchas", http.StatusNotFound)
+		}
+	}
+}
@@ -1,12 +1,13 @@
 package captcha
 
 import (
+	crand "crypto/rand"
+	"encoding/binary"
 	"image"
+	"image/color"
 	"image/png"
 	"io"
-	"os"
-	"rand"
-	"time"
+	"math/rand"
 )
 
 const (
@@ -19,57 +20,101 @@ const (
 
 type CaptchaImage struct {
 	*image.NRGBA
-	primaryColor image.NRGBAColor
+	rng          *rand.Rand
+	primaryColor color.NRGBA
+	charset      Charset
 	numWidth     int
 	numHeight    int
 	dotSize      int
 }
 
-func init() {
-	rand.Seed(time.Seconds())
-}
-
 // NewImage returns a new captcha image of the given width and height with the
 // given slice of numbers, where each number must be in range 0-9.
 func NewImage(numbers []byte, width, height int) *CaptchaImage {
+	return NewImageWithOptions(numbers, width, height, Options{})
+}
+
+// NewImageWithOptions is like NewImage, but lets the caller configure the
+// background fill and distortion pipeline applied to the rendered digits
+// instead of getting NewImage's defaults (10 random circles, then a
+// single strike-through line). Options{} behaves exactly like NewImage.
+func NewImageWithOptions(numbers []byte, width, height int, opts Options) *CaptchaImage {
+	chars := make([]rune, len(numbers))
+	for i, n := range numbers {
+		chars[i] = rune('0' + n)
+	}
+	return newCharsetImage(digitsCharset, chars, width, height, opts)
+}
+
+// NewRandomImage generates a sequence of random characters from the given
+// charset with the given length, and returns a new captcha image of the
+// given width and height with the generated characters printed on it, and
+// the sequence of characters itself.
+func NewRandomImage(length, width, height int, charsetID CharsetID) (img *CaptchaImage, chars []rune) {
+	cs := charsetByID(charsetID)
+	chars = randomChars(cs, length)
+	img = newCharsetImage(cs, chars, width, height, Options{})
+	return
+}
+
+// newCharsetImage is the shared constructor behind NewImage and
+// NewRandomImage: it fills a new image of the given width and height
+// with opts' background pipeline (or DefaultBackground if none was
+// given), lays out and draws chars taken from cs on top, then applies
+// opts' distortion pipeline (or DefaultDistortions if none was given).
+func newCharsetImage(cs Charset, chars []rune, width, height int, opts Options) *CaptchaImage {
 	img := new(CaptchaImage)
-	img.NRGBA = image.NewNRGBA(width, height)
-	img.primaryColor = image.NRGBAColor{
-		uint8(rand.Intn(129)),
-		uint8(rand.Intn(129)),
-		uint8(rand.Intn(129)),
-		0xFF,
+	img.NRGBA = image.NewNRGBA(image.Rect(0, 0, width, height))
+	img.rng = rand.New(rand.NewSource(randSeed()))
+	img.charset = cs
+	img.primaryColor = color.NRGBA{
+		R: uint8(img.rng.Intn(129)),
+		G: uint8(img.rng.Intn(129)),
+		B: uint8(img.rng.Intn(129)),
+		A: 0xFF,
 	}
 	// Calculate sizes
-	img.calculateSizes(width, height, len(numbers))
-	// Draw background (10 random circles of random brightness)
-	img.fillWithCircles(10, img.dotSize)
+	img.calculateSizes(width, height, len(chars))
+	// Fill in the background
+	background := opts.Background
+	if background == nil {
+		background = DefaultBackground()
+	}
+	for _, d := range background {
+		d.Apply(img)
+	}
 	// Randomly position captcha inside the image
-	maxx := width - (img.numWidth+img.dotSize)*len(numbers) - img.dotSize
+	maxx := width - (img.numWidth+img.dotSize)*len(chars) - img.dotSize
 	maxy := height - img.numHeight - img.dotSize*2
-	x := rnd(img.dotSize*2, maxx)
-	y := rnd(img.dotSize*2, maxy)
-	// Draw numbers
-	for _, n := range numbers {
-		img.drawNumber(font[n], x, y)
+	x := img.rnd(img.dotSize*2, maxx)
+	y := img.rnd(img.dotSize*2, maxy)
+	// Draw characters
+	for _, r := range chars {
+		img.drawGlyph(cs.Glyph(r), x, y)
 		x += img.numWidth + img.dotSize
 	}
-	// Draw strike-through line
-	img.strikeThrough()
+	// Apply the distortion pipeline
+	distortions := opts.Distortions
+	if distortions == nil {
+		distortions = DefaultDistortions()
+	}
+	for _, d := range distortions {
+		d.Apply(img)
+	}
 	return img
 }
 
-// NewRandomImage generates a sequence of random numbers with the given length,
-// and returns a new captcha image of the given width and height with generated
-// numbers printed on it, and the sequence of numbers itself.
-func NewRandomImage(length, width, height int) (img *CaptchaImage, numbers []byte) {
-	numbers = randomNumbers(length)
-	img = NewImage(numbers, width, height)
-	return
+// randSeed returns a seed suitable for a fresh *rand.Rand, drawn from
+// crypto/rand so that concurrent captcha renders don't share (or race
+// over) a single seed or a single generator.
+func randSeed() int64 {
+	var b [8]byte
+	crand.Read(b[:])
+	return int64(binary.BigEndian.Uint64(b[:]))
 }
 
 // PNGEncode writes captcha image in PNG format into the given writer.
-func (img *CaptchaImage) PNGEncode(w io.Writer) os.Error {
+func (img *CaptchaImage) PNGEncode(w io.Writer) error {
 	return png.Encode(w, img)
 }
 
@@ -82,11 +127,11 @@ func (img *CaptchaImage) calculateSizes(width, height, ncount int) {
 		border = width / 5
 	}
 	// Convert everything to floats for calculations
-	w := float64(width-border*2)
-	h := float64(height-border*2)
+	w := float64(width - border*2)
+	h := float64(height - border*2)
 	// fw takes into account 1-dot spacing between numbers
-	fw := float64(fontWidth) + 1
-	fh := float64(fontHeight)
+	fw := float64(img.charset.Width()) + 1
+	fh := float64(img.charset.Height())
 	nc := float64(ncount)
 	// Calculate the width of a single number taking into account only the
 	// width of the image
@@ -107,22 +152,22 @@ func (img *CaptchaImage) calculateSizes(width, height, ncount int) {
 	img.numHeight = int(nh) - img.dotSize
 }
 
-func (img *CaptchaImage) drawHorizLine(color image.Color, fromX, toX, y int) {
+func (img *CaptchaImage) drawHorizLine(c color.Color, fromX, toX, y int) {
 	for x := fromX; x <= toX; x++ {
-		img.Set(x, y, color)
+		img.Set(x, y, c)
 	}
 }
 
-func (img *CaptchaImage) drawCircle(color image.Color, x, y, radius int) {
+func (img *CaptchaImage) drawCircle(c color.Color, x, y, radius int) {
 	f := 1 - radius
 	dfx := 1
 	dfy := -2 * radius
 	xx := 0
 	yy := radius
 
-	img.Set(x, y+radius, color)
-	img.Set(x, y-radius, color)
-	img.drawHorizLine(color, x-radius, x+radius, y)
+	img.Set(x, y+radius, c)
+	img.Set(x, y-radius, c)
+	img.drawHorizLine(c, x-radius, x+radius, y)
 
 	for xx < yy {
 		if f >= 0 {
@@ -133,54 +178,67 @@ func (img *CaptchaImage) drawCircle(color image.Color, x, y, radius int) {
 		xx++
 		dfx += 2
 		f += dfx
-		img.drawHorizLine(color, x-xx, x+xx, y+yy)
-		img.drawHorizLine(color, x-xx, x+xx, y-yy)
-		img.drawHorizLine(color, x-yy, x+yy, y+xx)
-		img.drawHorizLine(color, x-yy, x+yy, y-xx)
+		img.drawHorizLine(c, x-xx, x+xx, y+yy)
+		img.drawHorizLine(c, x-xx, x+xx, y-yy)
+		img.drawHorizLine(c, x-yy, x+yy, y+xx)
+		img.drawHorizLine(c, x-yy, x+yy, y-xx)
 	}
 }
 
 func (img *CaptchaImage) fillWithCircles(n, maxradius int) {
-	color := img.primaryColor
+	c := img.primaryColor
 	maxx := img.Bounds().Max.X
 	maxy := img.Bounds().Max.Y
 	for i := 0; i < n; i++ {
-		setRandomBrightness(&color, 255)
-		r := rnd(1, maxradius)
-		img.drawCircle(color, rnd(r, maxx-r), rnd(r, maxy-r), r)
+		img.setRandomBrightness(&c, 255)
+		r := img.rnd(1, maxradius)
+		img.drawCircle(c, img.rnd(r, maxx-r), img.rnd(r, maxy-r), r)
 	}
 }
 
+// clone returns a copy of img with its own pixel buffer, so drawing on
+// the copy (as GIFEncode does, once per frame) can't mutate img itself.
+func (img *CaptchaImage) clone() *CaptchaImage {
+	c := *img
+	c.NRGBA = image.NewNRGBA(img.Bounds())
+	copy(c.Pix, img.Pix)
+	return &c
+}
+
 func (img *CaptchaImage) strikeThrough() {
 	r := 0
 	maxx := img.Bounds().Max.X
 	maxy := img.Bounds().Max.Y
-	y := rnd(maxy/3, maxy-maxy/3)
+	y := img.rnd(maxy/3, maxy-maxy/3)
 	for x := 0; x < maxx; x += r {
-		r = rnd(1, img.dotSize/2-1)
-		y += rnd(-img.dotSize/2, img.dotSize/2)
+		r = img.rnd(1, img.dotSize/2-1)
+		y += img.rnd(-img.dotSize/2, img.dotSize/2)
 		if y <= 0 || y >= maxy {
-			y = rnd(maxy/3, maxy-maxy/3)
+			y = img.rnd(maxy/3, maxy-maxy/3)
 		}
 		img.drawCircle(img.primaryColor, x, y, r)
 	}
 }
 
-func (img *CaptchaImage) drawNumber(number []byte, x, y int) {
-	skf := rand.Float64() * float64(rnd(-maxSkew, maxSkew))
+func (img *CaptchaImage) drawGlyph(glyph []byte, x, y int) {
+	if glyph == nil {
+		return
+	}
+	w, h := img.charset.Width(), img.charset.Height()
+	skf := img.rng.Float64() * float64(img.rnd(-maxSkew, maxSkew))
 	xs := float64(x)
 	minr := img.dotSize / 2               // minumum radius
 	maxr := img.dotSize/2 + img.dotSize/4 // maximum radius
-	y += rnd(-minr, minr)
-	for yy := 0; yy < fontHeight; yy++ {
-		for xx := 0; xx < fontWidth; xx++ {
-			if number[yy*fontWidth+xx] != blackChar {
+	y += img.rnd(-minr, minr)
+	for yy := 0; yy < h; yy++ {
+		for xx := 0; xx < w; xx++ {
+			if glyph[yy*w+xx] != blackChar {
 				continue
 			}
 			// introduce random variations
-			or := rnd(minr, maxr)
-			ox := x + (xx * img.dotSize) + rnd(0, or/2)
-			oy := y + (yy * img.dotSize) + rnd(0, or/2)
+			or := img.rnd(minr, maxr)
+			ox := x + (xx * img.dotSize) + img.rnd(0, or/2)
+			oy := y + (yy * img.dotSize) + img.rnd(0, or/2)
 			img.drawCircle(img.primaryColor, ox, oy, or)
 		}
 		xs += skf
@@ -188,13 +246,13 @@ func (img *CaptchaImage) drawNumber(number []byte, x, y int) {
 	}
 }
 
-func setRandomBrightness(c *image.NRGBAColor, max uint8) {
+func (img *CaptchaImage) setRandomBrightness(c *color.NRGBA, max uint8) {
 	minc := min3(c.R, c.G, c.B)
 	maxc := max3(c.R, c.G, c.B)
 	if maxc > max {
 		return
 	}
-	n := rand.Intn(int(max-maxc)) - int(minc)
+	n := img.rng.Intn(int(max-maxc)) - int(minc)
 	c.R = uint8(int(c.R) + n)
 	c.G = uint8(int(c.G) + n)
 	c.B = uint8(int(c.B) + n)
@@ -222,7 +280,16 @@ func max3(x, y, z uint8) (o uint8) {
 	return
 }
 
-// rnd returns a random number in range [from, to].
+// rnd returns a random number in range [from, to], drawn from the
+// package-level generator. It is used outside the context of a single
+// CaptchaImage render (audio clips, math expressions, charset picks);
+// img.rnd below is its per-instance counterpart.
 func rnd(from, to int) int {
 	return rand.Intn(to+1-from) + from
-}
\ No newline at end of file
+}
+
+// rnd is img's own random number generator, so that concurrent
+// CaptchaImage renders never share (or contend on) state.
+func (img *CaptchaImage) rnd(from, to int) int {
+	return img.rng.Intn(to+1-from) + from
+}
@@ -0,0 +1,82 @@
+package captcha
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// NewMathImage returns a new captcha image of the given width and height
+// rendering a short arithmetic expression, such as "3+7=", along with
+// the numeric answer the user is expected to type. Requiring evaluation
+// rather than transcription makes the captcha harder for a bot that can
+// only segment and read digits.
+func NewMathImage(width, height int) (img *CaptchaImage, answer int) {
+	chars, answer := mathExpression()
+	img = newCharsetImage(mathCharset, chars, width, height, Options{})
+	return
+}
+
+// mathExpression picks a random single-digit addition or subtraction
+// expression, returning it as the rune sequence NewMathImage draws and
+// the integer it evaluates to. Subtraction operands are ordered so the
+// answer is never negative.
+func mathExpression() (chars []rune, answer int) {
+	a, b := rnd(0, 9), rnd(0, 9)
+	if rnd(0, 1) == 0 {
+		answer = a + b
+		return []rune{rune('0' + a), '+', rune('0' + b), '='}, answer
+	}
+	if b > a {
+		a, b = b, a
+	}
+	answer = a - b
+	return []rune{rune('0' + a), '-', rune('0' + b), '='}, answer
+}
+
+// encodeMathPayload and decodeMathPayload pack a math captcha's
+// expression and expected answer into the single []byte payload a
+// kindMath entry stores in globalStore, so NewMath/VerifyMath reuse the
+// same Store (and its expiration and GC) that digit captchas do instead
+// of keeping their own parallel map.
+func encodeMathPayload(chars []rune, answer int) []byte {
+	return []byte(string(chars) + "\x00" + strconv.Itoa(answer))
+}
+
+func decodeMathPayload(data []byte) (chars []rune, answer int, ok bool) {
+	parts := bytes.SplitN(data, []byte{0}, 2)
+	if len(parts) != 2 {
+		return nil, 0, false
+	}
+	n, err := strconv.Atoi(string(parts[1]))
+	if err != nil {
+		return nil, 0, false
+	}
+	return []rune(string(parts[0])), n, true
+}
+
+// NewMath generates a new arithmetic captcha, stashes its expression and
+// expected answer in the current Store, and returns the id used to
+// retrieve its image rendering from a Server, or to VerifyMath an
+// answer against it.
+func NewMath() string {
+	id := newID()
+	chars, answer := mathExpression()
+	globalStore.Set(id, taggedPayload(kindMath, encodeMathPayload(chars, answer)))
+	return id
+}
+
+// VerifyMath reports whether answer is the correct result of the
+// arithmetic expression stored under id. As with Verify, a correct
+// answer consumes id so it cannot be replayed, and a wrong one counts
+// against id's attempt limit until it too is invalidated; the check
+// runs atomically inside the Store.
+func VerifyMath(id string, answer int) bool {
+	return globalStore.Check(id, func(payload []byte) bool {
+		kind, data, ok := untagPayload(payload)
+		if !ok || kind != kindMath {
+			return false
+		}
+		_, want, ok := decodeMathPayload(data)
+		return ok && want == answer
+	})
+}
@@ -0,0 +1,171 @@
+package captcha
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Expiration is how long a captcha's digits are kept around waiting
+	// to be verified before they are garbage-collected.
+	Expiration = 10 * time.Minute
+
+	// DefaultMaxAttempts is how many times Verify may be called with a
+	// wrong answer for a given id before that id is invalidated.
+	DefaultMaxAttempts = 3
+
+	collectGarbageInterval = time.Minute
+)
+
+// Store is the interface for storing and retrieving captcha data. An id
+// is associated with an opaque payload (for the default digit captchas,
+// the sequence of digits the user must answer with). The default
+// in-memory implementation is good enough for single-process
+// deployments; multi-process deployments should plug in a Store backed
+// by a shared service (see the RedisStore note below).
+type Store interface {
+	// Set stores the payload for the given id, resetting its expiration
+	// and attempt count.
+	Set(id string, payload []byte)
+
+	// Get returns the payload for id. If clear is true, Get consumes the
+	// id so it can never be verified again. Get returns nil if the id
+	// does not exist, has expired, or was already consumed.
+	Get(id string, clear bool) (payload []byte)
+
+	// Check atomically evaluates match against the payload stored under
+	// id and reports its result, or false if id does not exist, has
+	// expired, or was already consumed. A match consumes id so it
+	// cannot be replayed; a non-match counts against id's attempt
+	// limit, invalidating id once that limit is reached. Because the
+	// whole check runs under the store's lock, two concurrent Checks
+	// against the same id can never both match.
+	Check(id string, match func(payload []byte) bool) bool
+}
+
+// SetCustomStore replaces the default in-memory store with one of the
+// caller's choosing, for example a RedisStore that shares captcha state
+// across multiple front-end processes. It must satisfy the Store
+// interface above; this package does not ship a Redis client itself.
+func SetCustomStore(s Store) {
+	globalStoreMu.Lock()
+	defer globalStoreMu.Unlock()
+	globalStore = s
+}
+
+// NewMemoryStore returns a Store backed by an in-memory map, the same
+// implementation globalStore uses by default, with maxAttempts as the
+// maximum number of wrong answers an id tolerates before it is
+// invalidated. Pass it to SetCustomStore to change the attempt limit
+// without having to reimplement Store's locking, expiration and GC from
+// scratch.
+func NewMemoryStore(maxAttempts int) Store {
+	return newMemoryStore(maxAttempts)
+}
+
+var (
+	globalStoreMu sync.Mutex
+	globalStore   Store = newMemoryStore(DefaultMaxAttempts)
+)
+
+// Payload kinds let the different captcha modes (digit, math, ...)
+// share the one Store/globalStore instance - and so its expiration, GC
+// and SetCustomStore pluggability - while keeping their own
+// answer-checking logic. The kind is the payload's first byte; New and
+// NewMath tag what they store, and Verify/VerifyMath/the Server check
+// the tag before trusting the rest.
+const (
+	kindDigits byte = iota
+	kindMath
+)
+
+func taggedPayload(kind byte, data []byte) []byte {
+	return append([]byte{kind}, data...)
+}
+
+// untagPayload splits a tagged payload back into its kind and data. It
+// reports ok false for an empty (i.e. absent) payload.
+func untagPayload(payload []byte) (kind byte, data []byte, ok bool) {
+	if len(payload) == 0 {
+		return 0, nil, false
+	}
+	return payload[0], payload[1:], true
+}
+
+type memoryEntry struct {
+	payload  []byte
+	expires  time.Time
+	attempts int
+}
+
+type memoryStore struct {
+	mu          sync.Mutex
+	entries     map[string]*memoryEntry
+	maxAttempts int
+}
+
+func newMemoryStore(maxAttempts int) *memoryStore {
+	s := &memoryStore{
+		entries:     make(map[string]*memoryEntry),
+		maxAttempts: maxAttempts,
+	}
+	go s.collectGarbageLoop()
+	return s
+}
+
+func (s *memoryStore) Set(id string, payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[id] = &memoryEntry{
+		payload: payload,
+		expires: time.Now().Add(Expiration),
+	}
+}
+
+func (s *memoryStore) Get(id string, clear bool) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok || time.Now().After(e.expires) {
+		return nil
+	}
+	if clear {
+		delete(s.entries, id)
+	}
+	return e.payload
+}
+
+func (s *memoryStore) Check(id string, match func(payload []byte) bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok || time.Now().After(e.expires) {
+		return false
+	}
+	if match(e.payload) {
+		delete(s.entries, id)
+		return true
+	}
+	e.attempts++
+	if e.attempts >= s.maxAttempts {
+		delete(s.entries, id)
+	}
+	return false
+}
+
+func (s *memoryStore) collectGarbageLoop() {
+	for range time.Tick(collectGarbageInterval) {
+		s.collectGarbage()
+	}
+}
+
+func (s *memoryStore) collectGarbage() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, id)
+		}
+	}
+}
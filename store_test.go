@@ -0,0 +1,138 @@
+package captcha
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// withFreshStore swaps in a new memoryStore for the duration of a test,
+// so tests don't interfere with each other (or anything else) through
+// the shared globalStore.
+func withFreshStore(maxAttempts int) func() {
+	old := globalStore
+	SetCustomStore(NewMemoryStore(maxAttempts))
+	return func() { SetCustomStore(old) }
+}
+
+func TestNewMemoryStoreConfigurableAttempts(t *testing.T) {
+	const maxAttempts = 1
+	defer withFreshStore(maxAttempts)()
+
+	id := New()
+	_, digits, _ := untagPayload(globalStore.Get(id, false))
+	wrong := make([]byte, len(digits))
+	copy(wrong, digits)
+	wrong[0] ^= 1
+
+	if Verify(id, wrong) {
+		t.Fatalf("Verify(wrong answer) = true, want false")
+	}
+	if Verify(id, digits) {
+		t.Fatalf("Verify(correct answer) after a single wrong attempt with maxAttempts=1 = true, want false: id should already be invalidated")
+	}
+}
+
+func TestVerifyConsumesID(t *testing.T) {
+	defer withFreshStore(DefaultMaxAttempts)()
+
+	id := New()
+	kind, digits, ok := untagPayload(globalStore.Get(id, false))
+	if !ok || kind != kindDigits {
+		t.Fatalf("New() did not store a kindDigits payload")
+	}
+
+	if !Verify(id, digits) {
+		t.Fatalf("Verify(correct answer) = false, want true")
+	}
+	if Verify(id, digits) {
+		t.Fatalf("Verify(same id, correct answer again) = true, want false: id must be consumed on success")
+	}
+}
+
+func TestVerifyConcurrentOnlyOneWins(t *testing.T) {
+	defer withFreshStore(DefaultMaxAttempts)()
+
+	id := New()
+	_, digits, _ := untagPayload(globalStore.Get(id, false))
+
+	const n = 20
+	var wg sync.WaitGroup
+	var successes int32
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			if Verify(id, digits) {
+				atomic.AddInt32(&successes, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&successes); got != 1 {
+		t.Fatalf("concurrent Verify calls with the correct answer: %d succeeded, want exactly 1", got)
+	}
+}
+
+func TestVerifyLockoutAfterMaxAttempts(t *testing.T) {
+	const maxAttempts = 3
+	defer withFreshStore(maxAttempts)()
+
+	id := New()
+	_, digits, _ := untagPayload(globalStore.Get(id, false))
+	wrong := make([]byte, len(digits))
+	copy(wrong, digits)
+	wrong[0] ^= 1 // guaranteed to differ from digits in at least one byte
+
+	for i := 0; i < maxAttempts-1; i++ {
+		if Verify(id, wrong) {
+			t.Fatalf("Verify(wrong answer) = true, want false")
+		}
+	}
+	// One more wrong attempt exhausts maxAttempts and invalidates id.
+	Verify(id, wrong)
+
+	if Verify(id, digits) {
+		t.Fatalf("Verify(correct answer) after exceeding max attempts = true, want false: id should be invalidated")
+	}
+}
+
+func TestVerifyMathConsumesID(t *testing.T) {
+	defer withFreshStore(DefaultMaxAttempts)()
+
+	id := NewMath()
+	_, data, ok := untagPayload(globalStore.Get(id, false))
+	if !ok {
+		t.Fatalf("NewMath() did not store a payload")
+	}
+	_, answer, ok := decodeMathPayload(data)
+	if !ok {
+		t.Fatalf("decodeMathPayload failed on NewMath's own payload")
+	}
+
+	if !VerifyMath(id, answer) {
+		t.Fatalf("VerifyMath(correct answer) = false, want true")
+	}
+	if VerifyMath(id, answer) {
+		t.Fatalf("VerifyMath(same id, correct answer again) = true, want false: id must be consumed on success")
+	}
+}
+
+func TestVerifyMathLockoutAfterMaxAttempts(t *testing.T) {
+	const maxAttempts = 3
+	defer withFreshStore(maxAttempts)()
+
+	id := NewMath()
+	_, data, _ := untagPayload(globalStore.Get(id, false))
+	_, answer, _ := decodeMathPayload(data)
+	wrong := answer + 1000 // arithmetic answers here never reach this range
+
+	for i := 0; i < maxAttempts; i++ {
+		VerifyMath(id, wrong)
+	}
+
+	if VerifyMath(id, answer) {
+		t.Fatalf("VerifyMath(correct answer) after exceeding max attempts = true, want false: id should be invalidated")
+	}
+}